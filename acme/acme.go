@@ -0,0 +1,198 @@
+// Package acme 负责通过 ACME（Let's Encrypt 及兼容服务）签发/续期 WebUI 用的TLS证书，
+// 证书和账户私钥都只在内存里流转，本包不落盘；调用方（main.go）也不缓存签发结果，
+// 每次判断是否续期都以iKuai上当前安装的证书为准。
+package acme
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	"github.com/go-acme/lego/v4/certcrypto"
+	"github.com/go-acme/lego/v4/certificate"
+	"github.com/go-acme/lego/v4/challenge/http01"
+	"github.com/go-acme/lego/v4/lego"
+	"github.com/go-acme/lego/v4/registration"
+)
+
+// Config 对应配置文件里的 acme: 小节
+type Config struct {
+	// Domains 是要签发证书覆盖的域名列表，第一个作为CN
+	Domains []string
+	// Email 用于ACME账户注册，续期/吊销提醒会发到这个邮箱
+	Email string
+	// KeyType 为空时默认 "ec256"，可选 ec256/ec384/rsa2048/rsa4096
+	KeyType string
+	// Challenge 为空时默认 "http-01"，可选 http-01/dns-01
+	Challenge string
+	// HTTPPort 是 http-01 验证时本地监听的端口，默认 "80"
+	HTTPPort string
+	// DNSProvider 在 Challenge 为 dns-01 时必填，如 alidns/cloudflare/tencentcloud
+	DNSProvider string
+	// ProviderCredentials 透传给对应DNS provider的凭据，各provider认哪些key见 newDNSProvider
+	ProviderCredentials map[string]string
+	// RenewThresholdDays 是距离证书到期还剩多少天时触发续期，默认30天
+	RenewThresholdDays int
+	// CADirURL 为空时使用 Let's Encrypt 生产环境目录地址，测试时可指向 staging 目录
+	CADirURL string
+}
+
+func (c Config) keyType() certcrypto.KeyType {
+	switch c.KeyType {
+	case "ec384":
+		return certcrypto.EC384
+	case "rsa2048":
+		return certcrypto.RSA2048
+	case "rsa4096":
+		return certcrypto.RSA4096
+	default:
+		return certcrypto.EC256
+	}
+}
+
+func (c Config) renewThreshold() time.Duration {
+	if c.RenewThresholdDays > 0 {
+		return time.Duration(c.RenewThresholdDays) * 24 * time.Hour
+	}
+	return 30 * 24 * time.Hour
+}
+
+func (c Config) httpPort() string {
+	if c.HTTPPort != "" {
+		return c.HTTPPort
+	}
+	return "80"
+}
+
+// Cert 是一次签发/续期拿到的结果
+type Cert struct {
+	CertPEM     []byte
+	KeyPEM      []byte
+	Fingerprint string
+	NotAfter    time.Time
+}
+
+// Obtain 走一遍完整的ACME签发流程：生成账户私钥、注册账户、配置challenge、申请证书。
+// 每次调用都会重新签发，是否需要续期由调用方先调用 NeedsRenewal 判断。
+func Obtain(cfg Config) (*Cert, error) {
+	if len(cfg.Domains) == 0 {
+		return nil, fmt.Errorf("acme: domains不能为空")
+	}
+
+	accountKey, err := generateKey(cfg.keyType())
+	if err != nil {
+		return nil, fmt.Errorf("生成ACME账户私钥失败: %v", err)
+	}
+
+	user := &acmeUser{email: cfg.Email, key: accountKey}
+
+	legoCfg := lego.NewConfig(user)
+	legoCfg.Certificate.KeyType = cfg.keyType()
+	if cfg.CADirURL != "" {
+		legoCfg.CADirURL = cfg.CADirURL
+	}
+
+	client, err := lego.NewClient(legoCfg)
+	if err != nil {
+		return nil, fmt.Errorf("创建ACME客户端失败: %v", err)
+	}
+
+	if err := configureChallenge(client, cfg); err != nil {
+		return nil, err
+	}
+
+	reg, err := client.Registration.Register(registration.RegisterOptions{TermsOfServiceAgreed: true})
+	if err != nil {
+		return nil, fmt.Errorf("注册ACME账户失败: %v", err)
+	}
+	user.registration = reg
+
+	res, err := client.Certificate.Obtain(certificate.ObtainRequest{
+		Domains: cfg.Domains,
+		Bundle:  true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("签发证书失败: %v", err)
+	}
+
+	fingerprint, notAfter, err := inspect(res.Certificate)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cert{
+		CertPEM:     res.Certificate,
+		KeyPEM:      res.PrivateKey,
+		Fingerprint: fingerprint,
+		NotAfter:    notAfter,
+	}, nil
+}
+
+// NeedsRenewal 判断certPEM是否已经进入cfg.RenewThresholdDays规定的续期窗口（已过期也算）；
+// certPEM解析失败时返回false和error，由调用方决定如何处理。
+func NeedsRenewal(certPEM []byte, cfg Config) (bool, error) {
+	_, notAfter, err := inspect(certPEM)
+	if err != nil {
+		return false, err
+	}
+	return time.Until(notAfter) <= cfg.renewThreshold(), nil
+}
+
+// Fingerprint 返回certPEM中叶子证书的sha256指纹（十六进制），用于和iKuai上当前安装的证书比对，
+// 避免签到同一张证书时还重复上传。
+func Fingerprint(certPEM []byte) (string, error) {
+	fingerprint, _, err := inspect(certPEM)
+	return fingerprint, err
+}
+
+func inspect(certPEM []byte) (fingerprint string, notAfter time.Time, err error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return "", time.Time{}, fmt.Errorf("解析证书PEM失败: 找不到PEM块")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("解析证书失败: %v", err)
+	}
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:]), cert.NotAfter, nil
+}
+
+func generateKey(keyType certcrypto.KeyType) (crypto.PrivateKey, error) {
+	switch keyType {
+	case certcrypto.EC384:
+		return ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case certcrypto.RSA2048:
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case certcrypto.RSA4096:
+		return rsa.GenerateKey(rand.Reader, 4096)
+	default:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	}
+}
+
+func configureChallenge(client *lego.Client, cfg Config) error {
+	switch cfg.Challenge {
+	case "dns-01":
+		provider, err := newDNSProvider(cfg.DNSProvider, cfg.ProviderCredentials)
+		if err != nil {
+			return err
+		}
+		if err := client.Challenge.SetDNS01Provider(provider); err != nil {
+			return fmt.Errorf("配置dns-01 challenge失败: %v", err)
+		}
+	default:
+		if err := client.Challenge.SetHTTP01Provider(http01.NewProviderServer("", cfg.httpPort())); err != nil {
+			return fmt.Errorf("配置http-01 challenge失败: %v", err)
+		}
+	}
+	return nil
+}
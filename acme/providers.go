@@ -0,0 +1,34 @@
+package acme
+
+import (
+	"fmt"
+
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/go-acme/lego/v4/providers/dns/alidns"
+	"github.com/go-acme/lego/v4/providers/dns/cloudflare"
+	"github.com/go-acme/lego/v4/providers/dns/tencentcloud"
+)
+
+// newDNSProvider 根据provider名字和凭据构造对应的dns-01 challenge.Provider。
+// 凭据的key是本函数自己约定的：alidns 读 api-key/secret-key，cloudflare 读 api-token，
+// tencentcloud 读 secret-id/secret-key，其余key被忽略。
+func newDNSProvider(provider string, creds map[string]string) (challenge.Provider, error) {
+	switch provider {
+	case "alidns":
+		cfg := alidns.NewDefaultConfig()
+		cfg.APIKey = creds["api-key"]
+		cfg.SecretKey = creds["secret-key"]
+		return alidns.NewDNSProviderConfig(cfg)
+	case "cloudflare":
+		cfg := cloudflare.NewDefaultConfig()
+		cfg.AuthToken = creds["api-token"]
+		return cloudflare.NewDNSProviderConfig(cfg)
+	case "tencentcloud":
+		cfg := tencentcloud.NewDefaultConfig()
+		cfg.SecretID = creds["secret-id"]
+		cfg.SecretKey = creds["secret-key"]
+		return tencentcloud.NewDNSProviderConfig(cfg)
+	default:
+		return nil, fmt.Errorf("acme: 不支持的dns provider: %s", provider)
+	}
+}
@@ -0,0 +1,19 @@
+package acme
+
+import (
+	"crypto"
+
+	"github.com/go-acme/lego/v4/registration"
+)
+
+// acmeUser 实现 lego 要求的 registration.User 接口，账户私钥只在一次 Obtain 调用里临时生成，
+// 不做跨周期持久化——每次续期都当作新账户注册一次，换取"不用额外管理账户状态文件"的简单性。
+type acmeUser struct {
+	email        string
+	key          crypto.PrivateKey
+	registration *registration.Resource
+}
+
+func (u *acmeUser) GetEmail() string                        { return u.email }
+func (u *acmeUser) GetRegistration() *registration.Resource { return u.registration }
+func (u *acmeUser) GetPrivateKey() crypto.PrivateKey         { return u.key }
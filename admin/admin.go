@@ -0,0 +1,113 @@
+// Package admin 在可选的 listen 地址上暴露一个运维用的HTTP端点：
+// /metrics 给Prometheus抓取，/healthz 给反代做健康检查，/-/reload 重新读取配置文件，
+// POST /-/run 手动触发一次同步，/status 返回最近一次同步的结构化结果。
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// RunStatus 记录一次同步（无论来自cron还是手动触发）的结果
+type RunStatus struct {
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Success    bool      `json:"success"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Server 是admin端点的实现。它自己持有一把互斥锁，cron的定时触发和/-/run的手动触发
+// 共用同一个Trigger入口，保证两者不会同时跑起来。
+type Server struct {
+	run    func() error
+	reload func() error
+
+	runMu sync.Mutex // 保证同步和/-/reload不会和彼此并发：reload会整体替换run读取的全局配置
+
+	statusMu sync.RWMutex
+	status   RunStatus
+}
+
+// New 创建一个Server，run是实际执行一次同步的函数（对应main.go里的update），
+// reload是重新读取配置文件的函数（对应main.go里的readConf）。
+func New(run func() error, reload func() error) *Server {
+	return &Server{run: run, reload: reload}
+}
+
+// Trigger 执行一次同步，并记录结果供/status查询。cron和/-/run都应该走这个入口。
+func (s *Server) Trigger() RunStatus {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	status := RunStatus{StartedAt: time.Now()}
+	if err := s.run(); err != nil {
+		status.Error = err.Error()
+	} else {
+		status.Success = true
+	}
+	status.FinishedAt = time.Now()
+
+	s.statusMu.Lock()
+	s.status = status
+	s.statusMu.Unlock()
+
+	return status
+}
+
+func (s *Server) lastStatus() RunStatus {
+	s.statusMu.RLock()
+	defer s.statusMu.RUnlock()
+	return s.status
+}
+
+// Handler 返回admin端点的http.Handler，main.go负责用http.ListenAndServe把它监听起来。
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/healthz", s.handleHealthz)
+	mux.HandleFunc("/status", s.handleStatus)
+	mux.HandleFunc("/-/reload", s.handleReload)
+	mux.HandleFunc("/-/run", s.handleRun)
+	return mux
+}
+
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.lastStatus())
+}
+
+func (s *Server) handleReload(w http.ResponseWriter, r *http.Request) {
+	// reload会整体替换全局配置，必须和Trigger共用runMu，否则可能在一次同步读配置读到一半时被替换掉
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	if err := s.reload(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) handleRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "只支持POST", http.StatusMethodNotAllowed)
+		return
+	}
+
+	status := s.Trigger()
+
+	w.Header().Set("Content-Type", "application/json")
+	if !status.Success {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,47 @@
+package api
+
+import "fmt"
+
+// UploadCert 把证书和私钥上传到iKuai的证书库，name是展示用的证书名称，返回新证书的id。
+//
+// 注意：这里假设的是"cert"这个接口func_name，和 sys_cert/ssl_cert 之类的命名一样未经真机验证；
+// 接入真实设备前建议先用浏览器开发者工具抓一次iKuai后台上传证书的请求确认func_name和参数名。
+func (k *IKuai) UploadCert(name string, certPEM, keyPEM []byte) (id int, err error) {
+	var resp struct {
+		Data struct {
+			ID int `json:"id"`
+		} `json:"Data"`
+	}
+	params := map[string]any{
+		"name": name,
+		"cert": string(certPEM),
+		"key":  string(keyPEM),
+	}
+	if err := k.call("cert", "add", params, &resp); err != nil {
+		return 0, fmt.Errorf("上传证书%s失败: %v", name, err)
+	}
+	return resp.Data.ID, nil
+}
+
+// SetWebUICert 把WebUI管理页使用的证书切换为指定id对应的那张。
+func (k *IKuai) SetWebUICert(id int) error {
+	params := map[string]any{"cert_id": id}
+	if err := k.call("sys_ui", "set_cert", params, nil); err != nil {
+		return fmt.Errorf("设置WebUI证书失败: %v", err)
+	}
+	return nil
+}
+
+// CurrentWebUICertPEM 返回当前WebUI证书的PEM编码内容，调用方可以用 acme.NeedsRenewal/acme.Fingerprint
+// 解析它来判断是否已进入续期窗口、以及和新签发的证书比较指纹。没有安装证书时返回空切片。
+func (k *IKuai) CurrentWebUICertPEM() ([]byte, error) {
+	var resp struct {
+		Data struct {
+			Cert string `json:"cert"`
+		} `json:"Data"`
+	}
+	if err := k.call("sys_ui", "get_cert", map[string]any{"TYPE": "cert"}, &resp); err != nil {
+		return nil, fmt.Errorf("获取WebUI当前证书信息失败: %v", err)
+	}
+	return []byte(resp.Data.Cert), nil
+}
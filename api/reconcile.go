@@ -0,0 +1,145 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ztc1997/ikuai-bypass/reconcile"
+)
+
+// 本工具管理的每条记录都把 "bypass:<key>#<hash>" 写进 iKuai 记录自带的 remark 字段里，
+// 延续既有的"靠remark识别哪些记录归本工具管理"的做法（避免误删用户手工添加的条目），
+// 同时把增量同步需要用到的 key 和内容哈希也一起带上，不用额外维护一份本地状态文件。
+// Add*Tagged 是新增记录时写这个remark的唯一入口，下一轮 List* 才能认出这些记录、
+// 达到"不变的条目不会被重新下发"的稳定点。
+const bypassRemarkPrefix = "bypass:"
+
+func bypassRemark(key, hash string) string {
+	return bypassRemarkPrefix + key + "#" + hash
+}
+
+func parseBypassRemark(remark string) (key, hash string, ok bool) {
+	if !strings.HasPrefix(remark, bypassRemarkPrefix) {
+		return "", "", false
+	}
+	rest := strings.TrimPrefix(remark, bypassRemarkPrefix)
+	idx := strings.LastIndex(rest, "#")
+	if idx < 0 {
+		return "", "", false
+	}
+	return rest[:idx], rest[idx+1:], true
+}
+
+func (k *IKuai) listBypassEntries(funcName string) (map[string]reconcile.Entry, error) {
+	var resp struct {
+		Data []struct {
+			ID     int    `json:"id"`
+			Remark string `json:"remark"`
+		} `json:"Data"`
+	}
+	if err := k.call(funcName, "show", map[string]any{"TYPE": "total"}, &resp); err != nil {
+		return nil, fmt.Errorf("获取%s列表失败: %v", funcName, err)
+	}
+
+	result := make(map[string]reconcile.Entry, len(resp.Data))
+	for _, row := range resp.Data {
+		key, hash, ok := parseBypassRemark(row.Remark)
+		if !ok {
+			continue
+		}
+		result[key] = reconcile.Entry{ID: row.ID, Hash: hash}
+	}
+	return result, nil
+}
+
+func (k *IKuai) deleteBypassEntry(funcName string, id int) error {
+	if err := k.call(funcName, "del", map[string]any{"id": id}, nil); err != nil {
+		return fmt.Errorf("删除%s的记录%d失败: %v", funcName, id, err)
+	}
+	return nil
+}
+
+// addBypassEntry 新增一条记录，并把key和内容哈希以bypassRemark的格式写进remark字段，
+// fields是该记录类型特有的字段，remark由这里统一补上，调用方不用关心格式。
+func (k *IKuai) addBypassEntry(funcName, key, hash string, fields map[string]any) error {
+	params := make(map[string]any, len(fields)+1)
+	for field, v := range fields {
+		params[field] = v
+	}
+	params["remark"] = bypassRemark(key, hash)
+
+	if err := k.call(funcName, "add", params, nil); err != nil {
+		return fmt.Errorf("新增%s记录失败: %v", funcName, err)
+	}
+	return nil
+}
+
+// ListIKuaiBypassCustomIsp 返回当前下发的自定义运营商记录，key对应custom-isp配置里的name加分片序号
+func (k *IKuai) ListIKuaiBypassCustomIsp() (map[string]reconcile.Entry, error) {
+	return k.listBypassEntries("custom_isp")
+}
+
+// ListIKuaiBypassIpGroup 返回当前下发的IP分组记录
+func (k *IKuai) ListIKuaiBypassIpGroup() (map[string]reconcile.Entry, error) {
+	return k.listBypassEntries("ip_group")
+}
+
+// ListIKuaiBypassStreamDomain 返回当前下发的域名分流记录
+func (k *IKuai) ListIKuaiBypassStreamDomain() (map[string]reconcile.Entry, error) {
+	return k.listBypassEntries("acl_l7")
+}
+
+// ListIKuaiBypassStreamIpPort 返回当前下发的端口分流记录
+func (k *IKuai) ListIKuaiBypassStreamIpPort() (map[string]reconcile.Entry, error) {
+	return k.listBypassEntries("acl_mac_ip_port")
+}
+
+// DeleteCustomIspByID 删除单条自定义运营商记录，供增量同步使用；DelIKuaiBypassCustomIsp 仍保留用于全量清空。
+func (k *IKuai) DeleteCustomIspByID(id int) error { return k.deleteBypassEntry("custom_isp", id) }
+
+// DeleteIpGroupByID 删除单条IP分组记录
+func (k *IKuai) DeleteIpGroupByID(id int) error { return k.deleteBypassEntry("ip_group", id) }
+
+// DeleteStreamDomainByID 删除单条域名分流记录
+func (k *IKuai) DeleteStreamDomainByID(id int) error { return k.deleteBypassEntry("acl_l7", id) }
+
+// DeleteStreamIpPortByID 删除单条端口分流记录
+func (k *IKuai) DeleteStreamIpPortByID(id int) error {
+	return k.deleteBypassEntry("acl_mac_ip_port", id)
+}
+
+// AddCustomIspTagged 新增一条自定义运营商记录，key和hash由调用方按reconcile的desired map传入
+func (k *IKuai) AddCustomIspTagged(key, hash, name, ipGroup string) error {
+	return k.addBypassEntry("custom_isp", key, hash, map[string]any{
+		"group_name": name,
+		"ip_group":   ipGroup,
+	})
+}
+
+// AddIpGroupTagged 新增一条IP分组记录
+func (k *IKuai) AddIpGroupTagged(key, hash, name, ipGroup string) error {
+	return k.addBypassEntry("ip_group", key, hash, map[string]any{
+		"group_name": name,
+		"addr_pool":  ipGroup,
+	})
+}
+
+// AddStreamDomainTagged 新增一条域名分流记录
+func (k *IKuai) AddStreamDomainTagged(key, hash, iface, srcAddr, domain string) error {
+	return k.addBypassEntry("acl_l7", key, hash, map[string]any{
+		"interface": iface,
+		"src_addr":  srcAddr,
+		"group":     domain,
+	})
+}
+
+// AddStreamIpPortTagged 新增一条端口分流记录
+func (k *IKuai) AddStreamIpPortTagged(key, hash, type_, iface, ipGroup, srcAddr, nexthop string) error {
+	return k.addBypassEntry("acl_mac_ip_port", key, hash, map[string]any{
+		"type":      type_,
+		"interface": iface,
+		"ip_group":  ipGroup,
+		"src_addr":  srcAddr,
+		"nexthop":   nexthop,
+	})
+}
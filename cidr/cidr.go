@@ -0,0 +1,107 @@
+// Package cidr 提供基于 net/netip 的地址族分类与CIDR规整，
+// 取代原来"包含冒号就当成IPv6丢掉"的粗暴判断，让IPv4/IPv6可以分别或同时处理。
+package cidr
+
+import (
+	"net/netip"
+	"sort"
+	"strings"
+)
+
+// Family 是一条CIDR/地址所属的地址族
+type Family int
+
+const (
+	FamilyV4 Family = iota
+	FamilyV6
+)
+
+// ParsePrefix 把一行输入解析成netip.Prefix：支持"1.2.3.0/24"这样的CIDR，
+// 也兼容只有单个地址的"1.2.3.4"/"::1"（分别等价于/32、/128）。
+func ParsePrefix(s string) (netip.Prefix, error) {
+	s = strings.TrimSpace(s)
+
+	if p, err := netip.ParsePrefix(s); err == nil {
+		return p, nil
+	}
+
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// FamilyOf 返回p所属的地址族
+func FamilyOf(p netip.Prefix) Family {
+	if p.Addr().Is4() || p.Addr().Is4In6() {
+		return FamilyV4
+	}
+	return FamilyV6
+}
+
+// Split 把输入的地址/CIDR列表按地址族拆分成v4和v6两组，解析失败的行直接跳过。
+func Split(entries []string) (v4, v6 []string) {
+	for _, e := range entries {
+		p, err := ParsePrefix(e)
+		if err != nil {
+			continue
+		}
+		if FamilyOf(p) == FamilyV4 {
+			v4 = append(v4, p.Masked().String())
+		} else {
+			v6 = append(v6, p.Masked().String())
+		}
+	}
+	return v4, v6
+}
+
+// Normalize 对同一地址族内的CIDR列表去重，并剔除被其他条目完全覆盖的子网，
+// 覆盖范围不变。相邻网段不会被合并成更短的前缀（如两个/25不会合成一个/24）。
+// 调用前需保证entries都属于同一个地址族。
+func Normalize(entries []string) []string {
+	seen := make(map[string]bool, len(entries))
+	prefixes := make([]netip.Prefix, 0, len(entries))
+	for _, e := range entries {
+		p, err := ParsePrefix(e)
+		if err != nil {
+			continue
+		}
+		p = p.Masked()
+		key := p.String()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		prefixes = append(prefixes, p)
+	}
+
+	// 先按前缀长度从短到长排序，这样遍历时更短（覆盖范围更大）的网段总是先被加入result，
+	// 后面重叠的更长网段就能被判定为"已被覆盖"而跳过。
+	sort.Slice(prefixes, func(i, j int) bool {
+		if prefixes[i].Bits() != prefixes[j].Bits() {
+			return prefixes[i].Bits() < prefixes[j].Bits()
+		}
+		return prefixes[i].Addr().String() < prefixes[j].Addr().String()
+	})
+
+	var result []netip.Prefix
+	for _, p := range prefixes {
+		covered := false
+		for _, existing := range result {
+			if existing.Bits() <= p.Bits() && existing.Overlaps(p) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			result = append(result, p)
+		}
+	}
+
+	out := make([]string, len(result))
+	for i, p := range result {
+		out[i] = p.String()
+	}
+	return out
+}
@@ -0,0 +1,51 @@
+package cidr
+
+import "testing"
+
+func TestParsePrefix(t *testing.T) {
+	cases := map[string]string{
+		"1.2.3.0/24":    "1.2.3.0/24",
+		"1.2.3.4":       "1.2.3.4/32",
+		"::1":           "::1/128",
+		"2001:db8::/32": "2001:db8::/32",
+	}
+	for in, want := range cases {
+		p, err := ParsePrefix(in)
+		if err != nil {
+			t.Errorf("ParsePrefix(%q) 返回了error: %v", in, err)
+			continue
+		}
+		if got := p.String(); got != want {
+			t.Errorf("ParsePrefix(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	if _, err := ParsePrefix("not-an-address"); err == nil {
+		t.Error("ParsePrefix对非法输入应该返回error")
+	}
+}
+
+func TestSplit(t *testing.T) {
+	v4, v6 := Split([]string{"1.2.3.0/24", "::1", "bad-entry", "10.0.0.1"})
+
+	if len(v4) != 2 || v4[0] != "1.2.3.0/24" || v4[1] != "10.0.0.1/32" {
+		t.Errorf("v4 = %v, want [1.2.3.0/24 10.0.0.1/32]", v4)
+	}
+	if len(v6) != 1 || v6[0] != "::1/128" {
+		t.Errorf("v6 = %v, want [::1/128]", v6)
+	}
+}
+
+func TestNormalizeDedupesAndCollapsesOverlaps(t *testing.T) {
+	got := Normalize([]string{"10.0.0.0/8", "10.1.0.0/16", "10.0.0.0/8", "192.168.0.0/24"})
+
+	want := map[string]bool{"10.0.0.0/8": true, "192.168.0.0/24": true}
+	if len(got) != len(want) {
+		t.Fatalf("Normalize = %v, want %v", got, want)
+	}
+	for _, p := range got {
+		if !want[p] {
+			t.Errorf("Normalize返回了意料之外的条目 %q", p)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package geosite
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseClash 解析 clash/mihomo 的 domain:/full:/keyword:/regexp: 前缀文本格式。
+// full: 能直接表达成 iKuai 能接受的字面量域名；domain: 本身覆盖该域名及其所有子域，
+// 压成单条字面量会丢掉子域部分，因此也记一条warning；keyword:/regexp: 完全无法表达。
+func parseClash(body []byte) ([]string, []string, error) {
+	var domains []string
+	var warnings []string
+
+	for _, line := range splitNonEmptyLines(body) {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "full:"):
+			domains = append(domains, strings.TrimPrefix(line, "full:"))
+		case strings.HasPrefix(line, "domain:"):
+			domain := strings.TrimPrefix(line, "domain:")
+			domains = append(domains, domain)
+			warnings = append(warnings, fmt.Sprintf("domain规则本应匹配该域名及其所有子域，已收窄为单条字面量域名: %s", domain))
+		case strings.HasPrefix(line, "keyword:"):
+			warnings = append(warnings, fmt.Sprintf("keyword规则无法转换为iKuai字面量域名，已跳过: %s", line))
+		case strings.HasPrefix(line, "regexp:"):
+			warnings = append(warnings, fmt.Sprintf("regexp规则无法转换为iKuai字面量域名，已跳过: %s", line))
+		default:
+			// 没有前缀的裸域名按clash约定等价于 "domain:"，同样覆盖该域名及其所有子域，
+			// 收窄成单条字面量域名时也要警告，和上面domain:分支保持一致
+			domains = append(domains, line)
+			warnings = append(warnings, fmt.Sprintf("裸域名按clash约定等价于domain规则，本应匹配其所有子域，已收窄为单条字面量域名: %s", line))
+		}
+	}
+
+	return domains, warnings, nil
+}
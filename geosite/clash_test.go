@@ -0,0 +1,27 @@
+package geosite
+
+import "testing"
+
+func TestParseClash(t *testing.T) {
+	body := []byte("# comment\nfull:exact.example.com\ndomain:example.com\nkeyword:ads\nregexp:^evil\\.\nbare.example.com\n")
+
+	domains, warnings, err := parseClash(body)
+	if err != nil {
+		t.Fatalf("parseClash返回了error: %v", err)
+	}
+
+	wantDomains := []string{"exact.example.com", "example.com", "bare.example.com"}
+	if len(domains) != len(wantDomains) {
+		t.Fatalf("domains = %v, want %v", domains, wantDomains)
+	}
+	for i, d := range wantDomains {
+		if domains[i] != d {
+			t.Errorf("domains[%d] = %q, want %q", i, domains[i], d)
+		}
+	}
+
+	// domain:/裸域名/keyword:/regexp: 都应该产生warning，只有full:不应该
+	if len(warnings) != 4 {
+		t.Fatalf("warnings = %v, want 4条(domain收窄+裸域名收窄+keyword+regexp)", warnings)
+	}
+}
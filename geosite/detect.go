@@ -0,0 +1,41 @@
+package geosite
+
+import (
+	"bytes"
+	"unicode/utf8"
+)
+
+// srsMagic 是 sing-box 编译后 .srs 规则集文件的固定文件头
+var srsMagic = []byte{'S', 'R', 'S'}
+
+// detect 在没有显式声明 format 时，按内容特征猜测格式：
+// SRS 以固定魔数开头；geosite(dlc.dat) 是 protobuf 二进制，不会是合法 UTF-8 文本；
+// 文本格式里出现 clash 的 "domain:"/"full:"/"keyword:"/"regexp:" 前缀就按 clash 处理，否则按纯文本列表处理。
+func detect(body []byte) Format {
+	if bytes.HasPrefix(body, srsMagic) {
+		return FormatSRS
+	}
+	if !isValidUTF8Text(body) {
+		return FormatGeosite
+	}
+	for _, line := range splitNonEmptyLines(body) {
+		switch {
+		case hasAnyPrefix(line, "domain:", "full:", "keyword:", "regexp:"):
+			return FormatClash
+		}
+	}
+	return FormatPlain
+}
+
+func hasAnyPrefix(s string, prefixes ...string) bool {
+	for _, p := range prefixes {
+		if len(s) >= len(p) && s[:len(p)] == p {
+			return true
+		}
+	}
+	return false
+}
+
+func isValidUTF8Text(body []byte) bool {
+	return utf8.Valid(body)
+}
@@ -0,0 +1,74 @@
+// Package geosite 解析社区常见的域名规则集格式——v2ray 的 geosite(dlc.dat) protobuf、
+// sing-box 编译后的 SRS 二进制规则集、clash/mihomo 的 domain:/full:/keyword:/regexp: 文本格式，
+// 统一转换成 iKuai 域名分流字段能直接使用的字面量域名列表。
+//
+// iKuai 的域名分流只认字面量（等价于 full 匹配），keyword/regexp 规则无法表达，
+// 因此这些条目不会进入返回的域名列表，但也不会被静默丢弃，而是作为 warning 返回，由调用方记录日志提示用户。
+package geosite
+
+import "fmt"
+
+// Format 是一条 stream-domain 配置声明的解析格式
+type Format string
+
+const (
+	FormatAuto    Format = "auto"
+	FormatPlain   Format = "plain"
+	FormatGeosite Format = "geosite"
+	FormatClash   Format = "clash"
+	FormatSRS     Format = "srs"
+)
+
+// Parse 按 format 解析 body，tags 仅对 geosite 格式生效，用于从一个 bundle 里挑出指定分类
+// （如 "cn"、"geolocation-!cn"）。返回展开后的字面量域名列表，以及无法表达成字面量、
+// 需要提醒用户的规则描述。
+func Parse(format Format, body []byte, tags []string) (domains []string, warnings []string, err error) {
+	if format == FormatAuto || format == "" {
+		format = detect(body)
+	}
+
+	switch format {
+	case FormatPlain:
+		return parsePlain(body), nil, nil
+	case FormatClash:
+		return parseClash(body)
+	case FormatGeosite:
+		return parseGeosite(body, tags)
+	case FormatSRS:
+		return parseSRS(body)
+	default:
+		return nil, nil, fmt.Errorf("不支持的域名规则格式: %s", format)
+	}
+}
+
+func parsePlain(body []byte) []string {
+	return splitNonEmptyLines(body)
+}
+
+func splitNonEmptyLines(body []byte) []string {
+	var result []string
+	start := 0
+	for i, b := range body {
+		if b == '\n' {
+			if line := trimLine(body[start:i]); line != "" {
+				result = append(result, line)
+			}
+			start = i + 1
+		}
+	}
+	if line := trimLine(body[start:]); line != "" {
+		result = append(result, line)
+	}
+	return result
+}
+
+func trimLine(b []byte) string {
+	s := string(b)
+	for len(s) > 0 && (s[len(s)-1] == '\r' || s[len(s)-1] == ' ' || s[len(s)-1] == '\t') {
+		s = s[:len(s)-1]
+	}
+	for len(s) > 0 && (s[0] == ' ' || s[0] == '\t') {
+		s = s[1:]
+	}
+	return s
+}
@@ -0,0 +1,40 @@
+package geosite
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/sagernet/sing-box/common/srs"
+)
+
+// parseSRS 解析 sing-box 编译后的 SRS 二进制规则集，复用 sing-box 自带的 srs.Read
+// 而不是自己解析其内部的压缩+二进制编码格式。
+func parseSRS(body []byte) ([]string, []string, error) {
+	ruleSet, err := srs.Read(bytes.NewReader(body), true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("解析srs规则集失败: %v", err)
+	}
+
+	var domains []string
+	var warnings []string
+	for _, rule := range ruleSet.Options.Rules {
+		opts := rule.DefaultOptions
+
+		domains = append(domains, opts.Domain...)
+
+		for _, suffix := range opts.DomainSuffix {
+			// domain_suffix本应匹配该后缀及其所有子域，压成单条字面量会丢掉子域部分，记一条warning
+			domains = append(domains, suffix)
+			warnings = append(warnings, fmt.Sprintf("domain_suffix规则本应匹配该后缀及其所有子域，已收窄为单条字面量域名: %s", suffix))
+		}
+
+		for _, kw := range opts.DomainKeyword {
+			warnings = append(warnings, fmt.Sprintf("domain_keyword规则无法转换为iKuai字面量域名，已跳过: %s", kw))
+		}
+		for _, re := range opts.DomainRegex {
+			warnings = append(warnings, fmt.Sprintf("domain_regex规则无法转换为iKuai字面量域名，已跳过: %s", re))
+		}
+	}
+
+	return domains, warnings, nil
+}
@@ -0,0 +1,59 @@
+package geosite
+
+import (
+	"fmt"
+
+	"github.com/v2fly/v2ray-core/v5/app/router/routercommon"
+	"google.golang.org/protobuf/proto"
+)
+
+// parseGeosite 解析 v2ray 的 geosite(dlc.dat) protobuf格式，tags 为空时合并库里的全部分类，
+// 否则只取 tags 点名的分类（大小写不敏感，和geosite bundle里的country_code对应）。
+func parseGeosite(body []byte, tags []string) ([]string, []string, error) {
+	var list routercommon.GeoSiteList
+	if err := proto.Unmarshal(body, &list); err != nil {
+		return nil, nil, fmt.Errorf("解析geosite文件失败: %v", err)
+	}
+
+	wanted := make(map[string]bool, len(tags))
+	for _, t := range tags {
+		wanted[normalizeTag(t)] = true
+	}
+
+	var domains []string
+	var warnings []string
+	for _, site := range list.GetEntry() {
+		if len(wanted) > 0 && !wanted[normalizeTag(site.GetCountryCode())] {
+			continue
+		}
+		for _, d := range site.GetDomain() {
+			switch d.GetType() {
+			case routercommon.Domain_Full:
+				domains = append(domains, d.GetValue())
+			case routercommon.Domain_Plain:
+				// Plain是子串匹配，这里近似成一条字面量域名，匹配范围比原规则窄得多，记一条warning
+				domains = append(domains, d.GetValue())
+				warnings = append(warnings, fmt.Sprintf("plain规则是子串匹配，已收窄为单条字面量域名: %s:%s", site.GetCountryCode(), d.GetValue()))
+			case routercommon.Domain_RootDomain:
+				// RootDomain覆盖该域名及其所有子域，压成单条字面量会丢掉子域部分，记一条warning
+				domains = append(domains, d.GetValue())
+				warnings = append(warnings, fmt.Sprintf("root域名规则本应匹配其所有子域，已收窄为单条字面量域名: %s:%s", site.GetCountryCode(), d.GetValue()))
+			case routercommon.Domain_Regex:
+				warnings = append(warnings, fmt.Sprintf("regex规则无法转换为iKuai字面量域名，已跳过: %s:%s", site.GetCountryCode(), d.GetValue()))
+			}
+		}
+	}
+
+	return domains, warnings, nil
+}
+
+func normalizeTag(tag string) string {
+	result := make([]byte, 0, len(tag))
+	for _, c := range []byte(tag) {
+		if c >= 'A' && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		result = append(result, c)
+	}
+	return string(result)
+}
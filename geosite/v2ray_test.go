@@ -0,0 +1,44 @@
+package geosite
+
+import (
+	"testing"
+
+	"github.com/v2fly/v2ray-core/v5/app/router/routercommon"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestParseGeositeNarrowingWarnings(t *testing.T) {
+	list := &routercommon.GeoSiteList{
+		Entry: []*routercommon.GeoSite{
+			{
+				CountryCode: "CN",
+				Domain: []*routercommon.Domain{
+					{Type: routercommon.Domain_Full, Value: "full.example.com"},
+					{Type: routercommon.Domain_Plain, Value: "plain.example.com"},
+					{Type: routercommon.Domain_RootDomain, Value: "root.example.com"},
+					{Type: routercommon.Domain_Regex, Value: "^evil\\."},
+				},
+			},
+		},
+	}
+
+	body, err := proto.Marshal(list)
+	if err != nil {
+		t.Fatalf("构造测试fixture失败: %v", err)
+	}
+
+	domains, warnings, err := parseGeosite(body, nil)
+	if err != nil {
+		t.Fatalf("parseGeosite返回了error: %v", err)
+	}
+
+	wantDomains := []string{"full.example.com", "plain.example.com", "root.example.com"}
+	if len(domains) != len(wantDomains) {
+		t.Fatalf("domains = %v, want %v", domains, wantDomains)
+	}
+
+	// plain/root_domain都会收窄匹配范围，加上regex本身就无法表达，一共应该有3条warning
+	if len(warnings) != 3 {
+		t.Fatalf("warnings = %v, want 3条（plain收窄+root收窄+regex）", warnings)
+	}
+}
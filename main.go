@@ -3,7 +3,6 @@ package main
 import (
 	"flag"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"os"
@@ -11,32 +10,65 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/robfig/cron/v3"
+	"github.com/ztc1997/ikuai-bypass/acme"
+	"github.com/ztc1997/ikuai-bypass/admin"
 	"github.com/ztc1997/ikuai-bypass/api"
+	"github.com/ztc1997/ikuai-bypass/cidr"
+	"github.com/ztc1997/ikuai-bypass/geosite"
+	"github.com/ztc1997/ikuai-bypass/metrics"
+	"github.com/ztc1997/ikuai-bypass/mmdb"
+	"github.com/ztc1997/ikuai-bypass/reconcile"
 	"github.com/ztc1997/ikuai-bypass/router"
+	"github.com/ztc1997/ikuai-bypass/source"
 	"gopkg.in/yaml.v3"
 )
 
 var confPath = flag.String("c", "./config.yml", "配置文件路径")
+var dryRun = flag.Bool("dry-run", false, "只打印本次会同步的变更计划，不实际调用iKuai接口")
 
 var conf struct {
-	IkuaiURL  string `yaml:"ikuai-url"`
-	Username  string `yaml:"username"`
-	Password  string `yaml:"password"`
-	Cron      string `yaml:"cron"`
+	IkuaiURL string `yaml:"ikuai-url"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	Cron     string `yaml:"cron"`
+	// Listen 为空时不启动admin端点；非空时在该地址上暴露/metrics、/healthz等运维接口
+	Listen string `yaml:"listen"`
+	// CacheDir 为空时不启用"重启不重新下载"的本地快照缓存
+	CacheDir  string `yaml:"cache-dir"`
 	CustomIsp []struct {
 		Name string `yaml:"name"`
 		URL  string `yaml:"url"`
+		// Type 为空或"url"时按旧逻辑从URL拉取文本列表；为"mmdb-isp"时改为从MmdbFile读取并按Mmdb筛选
+		Type     string        `yaml:"type"`
+		MmdbFile string        `yaml:"mmdb-file"`
+		Mmdb     mmdb.Selector `yaml:"mmdb"`
+		// Family 为空或"v4"时只下发IPv4；"v6"只下发IPv6；"both"时v4和v6各生成一份iKuai条目，v6条目名加"_v6"后缀
+		Family string `yaml:"family"`
+		sourceOpts
 	} `yaml:"custom-isp"`
 	IpGroup []struct {
 		Name string `yaml:"name"`
 		URL  string `yaml:"url"`
+		// Type 为空或"url"时按旧逻辑从URL拉取文本列表；为"mmdb-ip-group"时改为从MmdbFile读取并按Mmdb筛选
+		Type     string        `yaml:"type"`
+		MmdbFile string        `yaml:"mmdb-file"`
+		Mmdb     mmdb.Selector `yaml:"mmdb"`
+		// Family 含义同 custom-isp 的 family
+		Family string `yaml:"family"`
+		sourceOpts
 	} `yaml:"ip-group"`
 	StreamDomain []struct {
 		Interface string `yaml:"interface"`
 		SrcAddr   string `yaml:"src-addr"`
 		URL       string `yaml:"url"`
+		// Format 为空或"auto"时按内容自动识别；可显式指定"plain"|"geosite"|"clash"|"srs"
+		Format string `yaml:"format"`
+		// Tags 仅对geosite格式生效，从bundle里挑选指定分类，如 ["cn", "geolocation-!cn"]
+		Tags []string `yaml:"tags"`
+		sourceOpts
 	} `yaml:"stream-domain"`
 	StreamIpPort []struct {
 		Type      string `yaml:"type"`
@@ -44,7 +76,59 @@ var conf struct {
 		Nexthop   string `yaml:"nexthop"`
 		SrcAddr   string `yaml:"src-addr"`
 		IpGroup   string `yaml:"ip-group"`
+		// Family 决定引用ip-group的哪个地址族版本："v4"(默认)/"v6"取对应版本，"both"把两个版本的成员都取出来
+		Family string `yaml:"family"`
 	} `yaml:"stream-ipport"`
+	// Acme 为空（Domains为空）时不启用WebUI证书自动签发/续期
+	Acme struct {
+		// Enabled 默认为false：UploadCert/SetWebUICert/获取当前证书用到的func_name和参数名
+		// 都还没有在真实iKuai设备上验证过，需要用户先抓包确认可用，再显式把这个开关打开
+		Enabled bool     `yaml:"enabled"`
+		Domains []string `yaml:"domains"`
+		Email   string   `yaml:"email"`
+		// KeyType 为空时默认 "ec256"，可选 ec256/ec384/rsa2048/rsa4096
+		KeyType string `yaml:"key-type"`
+		// Challenge 为空时默认 "http-01"，可选 http-01/dns-01
+		Challenge string `yaml:"challenge"`
+		// HTTPPort 仅http-01生效，默认"80"
+		HTTPPort string `yaml:"http-port"`
+		// DNSProvider 仅challenge为dns-01时必填，如 alidns/cloudflare/tencentcloud
+		DNSProvider string `yaml:"dns-provider"`
+		// Credentials 透传给DNSProvider，字段含义见对应provider文档
+		Credentials map[string]string `yaml:"credentials"`
+		// RenewThresholdDays 为0时默认30天
+		RenewThresholdDays int `yaml:"renew-threshold-days"`
+	} `yaml:"acme"`
+}
+
+// sourceOpts 是各条规则源共用的拉取选项，内嵌进每个 custom-isp/ip-group/stream-domain 条目
+type sourceOpts struct {
+	Etag   bool   `yaml:"etag"`
+	Sha256 string `yaml:"sha256"`
+	// MaxRetries 为0时使用source.Options的默认值（3次）
+	MaxRetries int `yaml:"max-retries"`
+	// InitialBackoff 形如"2s"，为空时使用source.Options的默认值（1s），每次重试按2倍递增
+	InitialBackoff string `yaml:"initial-backoff"`
+}
+
+func (o sourceOpts) toOptions() source.Options {
+	var backoff time.Duration
+	if o.InitialBackoff != "" {
+		d, err := time.ParseDuration(o.InitialBackoff)
+		if err != nil {
+			log.Printf("initial-backoff值%q无法解析，改用默认值: %v", o.InitialBackoff, err)
+		} else {
+			backoff = d
+		}
+	}
+
+	return source.Options{
+		CacheDir:       conf.CacheDir,
+		UseEtag:        o.Etag,
+		Sha256:         o.Sha256,
+		MaxRetries:     o.MaxRetries,
+		InitialBackoff: backoff,
+	}
 }
 
 // 临时存储新配置的数据结构
@@ -81,14 +165,25 @@ func main() {
 		return
 	}
 
-	update()
+	adminServer := admin.New(update, func() error { return readConf(*confPath) })
+
+	if conf.Listen != "" {
+		go func() {
+			log.Println("admin端点已启动：", conf.Listen)
+			if err := http.ListenAndServe(conf.Listen, adminServer.Handler()); err != nil {
+				log.Println("admin端点退出：", err)
+			}
+		}()
+	}
+
+	adminServer.Trigger()
 
 	if conf.Cron == "" {
 		return
 	}
 
 	c := cron.New()
-	_, err = c.AddFunc(conf.Cron, update)
+	_, err = c.AddFunc(conf.Cron, func() { adminServer.Trigger() })
 	if err != nil {
 		log.Println("启动计划任务失败：", err)
 		return
@@ -116,11 +211,13 @@ func readConf(filename string) error {
 	return nil
 }
 
-func update() {
+// update 执行一次完整的同步周期。返回值供admin包的/-/run和/status上报本次结果，
+// cron的定时触发和/-/run的手动触发都要经过admin.Server.Trigger，保证不会并发执行。
+func update() error {
 	err := readConf(*confPath)
 	if err != nil {
 		log.Println("更新配置文件失败：", err)
-		return
+		return err
 	}
 
 	baseurl := conf.IkuaiURL
@@ -128,7 +225,7 @@ func update() {
 		gateway, err := router.GetGateway()
 		if err != nil {
 			log.Println("获取默认网关失败：", err)
-			return
+			return err
 		}
 		baseurl = "http://" + gateway
 		log.Println("使用默认网关地址：", baseurl)
@@ -139,7 +236,7 @@ func update() {
 	err = iKuai.Login(conf.Username, conf.Password)
 	if err != nil {
 		log.Println("登陆失败：", err)
-		return
+		return err
 	} else {
 		log.Println("登录成功")
 	}
@@ -148,90 +245,341 @@ func update() {
 	newCustomIsps, err := fetchAllCustomIsp()
 	if err != nil {
 		log.Println("获取自定义运营商配置失败，终止更新：", err)
-		return
+		return err
 	}
 
 	newIpGroups, err := fetchAllIpGroup()
 	if err != nil {
 		log.Println("获取IP分组配置失败，终止更新：", err)
-		return
+		return err
 	}
 
 	newStreamDomains, err := fetchAllStreamDomain()
 	if err != nil {
 		log.Println("获取域名分流配置失败，终止更新：", err)
-		return
+		return err
 	}
 
 	newStreamIpPorts, err := fetchAllStreamIpPort(iKuai)
 	if err != nil {
 		log.Println("获取端口分流配置失败，终止更新：", err)
+		return err
+	}
+
+	// 2. 和iKuai上已有的记录做增量对比，只下发真正变化的部分（--dry-run 时只打印计划、不实际调用）
+	reconcileCustomIsps(iKuai, newCustomIsps)
+	reconcileIpGroups(iKuai, newIpGroups)
+	reconcileStreamDomains(iKuai, newStreamDomains)
+	reconcileStreamIpPorts(iKuai, newStreamIpPorts)
+
+	if conf.Acme.Enabled && len(conf.Acme.Domains) > 0 {
+		if err := renewWebUICert(iKuai); err != nil {
+			log.Println("WebUI证书签发/续期失败：", err)
+		}
+	}
+
+	metrics.LastRunTimestamp.Set(float64(time.Now().Unix()))
+	return nil
+}
+
+// renewWebUICert 在需要时签发/续期一张证书并装到iKuai的WebUI上：
+// 已安装证书未到续期阈值则跳过；签发出的新证书指纹和当前WebUI证书一致也跳过重新上传。
+func renewWebUICert(iKuai *api.IKuai) error {
+	cfg := acme.Config{
+		Domains:             conf.Acme.Domains,
+		Email:               conf.Acme.Email,
+		KeyType:             conf.Acme.KeyType,
+		Challenge:           conf.Acme.Challenge,
+		HTTPPort:            conf.Acme.HTTPPort,
+		DNSProvider:         conf.Acme.DNSProvider,
+		ProviderCredentials: conf.Acme.Credentials,
+		RenewThresholdDays:  conf.Acme.RenewThresholdDays,
+	}
+
+	currentCertPEM, err := iKuai.CurrentWebUICertPEM()
+	if err != nil {
+		log.Println("获取当前WebUI证书信息失败，继续尝试签发：", err)
+		currentCertPEM = nil
+	} else if len(currentCertPEM) > 0 {
+		renew, err := acme.NeedsRenewal(currentCertPEM, cfg)
+		if err != nil {
+			log.Println("解析当前WebUI证书失败，继续尝试签发：", err)
+		} else if !renew {
+			log.Println("WebUI证书尚未进入续期窗口，跳过本次签发")
+			return nil
+		}
+	}
+
+	var currentFingerprint string
+	if len(currentCertPEM) > 0 {
+		if fingerprint, err := acme.Fingerprint(currentCertPEM); err != nil {
+			log.Println("计算当前WebUI证书指纹失败：", err)
+		} else {
+			currentFingerprint = fingerprint
+		}
+	}
+
+	if *dryRun {
+		log.Println("[dry-run] WebUI证书: 将签发/续期，不实际调用ACME和iKuai接口")
+		return nil
+	}
+
+	cert, err := acme.Obtain(cfg)
+	if err != nil {
+		return fmt.Errorf("签发证书失败: %v", err)
+	}
+
+	if cert.Fingerprint == currentFingerprint {
+		log.Println("WebUI证书指纹未变化，跳过重新上传")
+		return nil
+	}
+
+	id, err := iKuai.UploadCert(strings.Join(conf.Acme.Domains, ","), cert.CertPEM, cert.KeyPEM)
+	if err != nil {
+		return fmt.Errorf("上传新证书失败: %v", err)
+	}
+	if err := iKuai.SetWebUICert(id); err != nil {
+		return fmt.Errorf("切换WebUI证书失败: %v", err)
+	}
+
+	log.Printf("WebUI证书已更新，新指纹：%s，到期时间：%s", cert.Fingerprint, cert.NotAfter.Format(time.RFC3339))
+	return nil
+}
+
+// reconcileCustomIsps 增量同步自定义运营商：只对内容变化的分片发起删除+新增，不再整体清空重建
+func reconcileCustomIsps(iKuai *api.IKuai, dataList []customIspData) {
+	current, err := iKuai.ListIKuaiBypassCustomIsp()
+	if err != nil {
+		log.Println("获取当前自定义运营商列表失败，跳过本次同步：", err)
+		return
+	}
+
+	desired := make(map[string]string)
+	content := make(map[string]string)
+	for _, data := range dataList {
+		for index, ig := range data.ipGroups {
+			key := fmt.Sprintf("%s#%d", data.name, index)
+			desired[key] = reconcile.ContentHash(ig...)
+			content[key] = strings.Join(ig, ",")
+		}
+	}
+
+	plan := reconcile.Diff(desired, current)
+	if *dryRun {
+		log.Printf("[dry-run] 自定义运营商: 新增%d 变更%d 删除%d 不变%d", len(plan.ToAdd), len(plan.ToUpdate), len(plan.ToDelete), plan.Unchanged)
 		return
 	}
 
-	// 2. 所有新配置获取成功后，删除旧配置
-	err = iKuai.DelIKuaiBypassCustomIsp()
+	for _, id := range plan.ToDelete {
+		if err := iKuai.DeleteCustomIspByID(id); err != nil {
+			log.Println("删除自定义运营商记录失败：", err)
+		}
+	}
+	for _, key := range append(append([]string{}, plan.ToAdd...), plan.ToUpdate...) {
+		name := strings.SplitN(key, "#", 2)[0]
+		if err := iKuai.AddCustomIspTagged(key, desired[key], name, content[key]); err != nil {
+			log.Printf("添加自定义运营商'%s'失败: %v", name, err)
+			metrics.ApplyTotal.WithLabelValues("custom-isp", "failure").Inc()
+		} else {
+			metrics.ApplyTotal.WithLabelValues("custom-isp", "success").Inc()
+		}
+	}
+	metrics.RulesInEffect.WithLabelValues("custom-isp").Set(float64(len(desired)))
+	log.Printf("自定义运营商同步完成：新增%d 变更%d 删除%d 不变%d", len(plan.ToAdd), len(plan.ToUpdate), len(plan.ToDelete), plan.Unchanged)
+}
+
+// reconcileIpGroups 增量同步IP分组
+func reconcileIpGroups(iKuai *api.IKuai, dataList []ipGroupData) {
+	current, err := iKuai.ListIKuaiBypassIpGroup()
 	if err != nil {
-		log.Println("移除旧的自定义运营商失败：", err)
-	} else {
-		log.Println("移除旧的自定义运营商成功")
+		log.Println("获取当前IP分组列表失败，跳过本次同步：", err)
+		return
 	}
 
-	err = iKuai.DelIKuaiBypassIpGroup()
+	desired := make(map[string]string)
+	content := make(map[string]string)
+	for _, data := range dataList {
+		for index, ig := range data.ipGroups {
+			key := data.name + "_" + strconv.Itoa(index)
+			desired[key] = reconcile.ContentHash(ig...)
+			content[key] = strings.Join(ig, ",")
+		}
+	}
+
+	plan := reconcile.Diff(desired, current)
+	if *dryRun {
+		log.Printf("[dry-run] IP分组: 新增%d 变更%d 删除%d 不变%d", len(plan.ToAdd), len(plan.ToUpdate), len(plan.ToDelete), plan.Unchanged)
+		return
+	}
+
+	for _, id := range plan.ToDelete {
+		if err := iKuai.DeleteIpGroupByID(id); err != nil {
+			log.Println("删除IP分组记录失败：", err)
+		}
+	}
+	for _, key := range append(append([]string{}, plan.ToAdd...), plan.ToUpdate...) {
+		if err := iKuai.AddIpGroupTagged(key, desired[key], key, content[key]); err != nil {
+			log.Printf("添加IP分组'%s'失败: %v", key, err)
+			metrics.ApplyTotal.WithLabelValues("ip-group", "failure").Inc()
+		} else {
+			metrics.ApplyTotal.WithLabelValues("ip-group", "success").Inc()
+		}
+	}
+	metrics.RulesInEffect.WithLabelValues("ip-group").Set(float64(len(desired)))
+	log.Printf("IP分组同步完成：新增%d 变更%d 删除%d 不变%d", len(plan.ToAdd), len(plan.ToUpdate), len(plan.ToDelete), plan.Unchanged)
+}
+
+// reconcileStreamDomains 增量同步域名分流
+func reconcileStreamDomains(iKuai *api.IKuai, dataList []streamDomainData) {
+	current, err := iKuai.ListIKuaiBypassStreamDomain()
 	if err != nil {
-		log.Println("移除旧的IP分组失败：", err)
-	} else {
-		log.Println("移除旧的IP分组成功")
+		log.Println("获取当前域名分流列表失败，跳过本次同步：", err)
+		return
+	}
+
+	desired := make(map[string]string)
+	content := make(map[string]string)
+	for _, data := range dataList {
+		for index, d := range data.domains {
+			key := fmt.Sprintf("%s|%s#%d", data.iface, data.srcAddr, index)
+			desired[key] = reconcile.ContentHash(d...)
+			content[key] = strings.Join(d, ",")
+		}
 	}
 
-	err = iKuai.DelIKuaiBypassStreamDomain()
+	plan := reconcile.Diff(desired, current)
+	if *dryRun {
+		log.Printf("[dry-run] 域名分流: 新增%d 变更%d 删除%d 不变%d", len(plan.ToAdd), len(plan.ToUpdate), len(plan.ToDelete), plan.Unchanged)
+		return
+	}
+
+	for _, id := range plan.ToDelete {
+		if err := iKuai.DeleteStreamDomainByID(id); err != nil {
+			log.Println("删除域名分流记录失败：", err)
+		}
+	}
+	for _, data := range dataList {
+		for index := range data.domains {
+			key := fmt.Sprintf("%s|%s#%d", data.iface, data.srcAddr, index)
+			if !contains(plan.ToAdd, key) && !contains(plan.ToUpdate, key) {
+				continue
+			}
+			if err := iKuai.AddStreamDomainTagged(key, desired[key], data.iface, data.srcAddr, content[key]); err != nil {
+				log.Printf("添加域名分流'%s'失败: %v", data.iface, err)
+				metrics.ApplyTotal.WithLabelValues("stream-domain", "failure").Inc()
+			} else {
+				metrics.ApplyTotal.WithLabelValues("stream-domain", "success").Inc()
+			}
+		}
+	}
+	metrics.RulesInEffect.WithLabelValues("stream-domain").Set(float64(len(desired)))
+	log.Printf("域名分流同步完成：新增%d 变更%d 删除%d 不变%d", len(plan.ToAdd), len(plan.ToUpdate), len(plan.ToDelete), plan.Unchanged)
+}
+
+// reconcileStreamIpPorts 增量同步端口分流，每条配置只对应一条iKuai记录，没有分片
+func reconcileStreamIpPorts(iKuai *api.IKuai, dataList []streamIpPortData) {
+	current, err := iKuai.ListIKuaiBypassStreamIpPort()
 	if err != nil {
-		log.Println("移除旧的域名分流失败：", err)
-	} else {
-		log.Println("移除旧的域名分流成功")
+		log.Println("获取当前端口分流列表失败，跳过本次同步：", err)
+		return
+	}
+
+	desired := make(map[string]string)
+	for _, data := range dataList {
+		key := data.type_ + "|" + data.iface + "|" + data.srcAddr + "|" + data.nexthop
+		desired[key] = reconcile.ContentHash(data.ipGroupList...)
+	}
+
+	plan := reconcile.Diff(desired, current)
+	if *dryRun {
+		log.Printf("[dry-run] 端口分流: 新增%d 变更%d 删除%d 不变%d", len(plan.ToAdd), len(plan.ToUpdate), len(plan.ToDelete), plan.Unchanged)
+		return
 	}
 
-	err = iKuai.DelIKuaiBypassStreamIpPort()
+	for _, id := range plan.ToDelete {
+		if err := iKuai.DeleteStreamIpPortByID(id); err != nil {
+			log.Println("删除端口分流记录失败：", err)
+		}
+	}
+	for _, data := range dataList {
+		key := data.type_ + "|" + data.iface + "|" + data.srcAddr + "|" + data.nexthop
+		if !contains(plan.ToAdd, key) && !contains(plan.ToUpdate, key) {
+			continue
+		}
+		if err := iKuai.AddStreamIpPortTagged(
+			key,
+			desired[key],
+			data.type_,
+			data.iface,
+			strings.Join(data.ipGroupList, ","),
+			data.srcAddr,
+			data.nexthop,
+		); err != nil {
+			log.Printf("添加端口分流'%s'失败: %v", data.iface, err)
+			metrics.ApplyTotal.WithLabelValues("stream-ipport", "failure").Inc()
+		} else {
+			metrics.ApplyTotal.WithLabelValues("stream-ipport", "success").Inc()
+		}
+	}
+	metrics.RulesInEffect.WithLabelValues("stream-ipport").Set(float64(len(desired)))
+	log.Printf("端口分流同步完成：新增%d 变更%d 删除%d 不变%d", len(plan.ToAdd), len(plan.ToUpdate), len(plan.ToDelete), plan.Unchanged)
+}
+
+// fetchSource 包一层Fetch，把拉取耗时/字节数/成败上报到metrics，三类规则源的拉取都走这里
+func fetchSource(name string, src source.Source) ([]byte, error) {
+	start := time.Now()
+	body, err := src.Fetch()
+	metrics.FetchDurationSeconds.WithLabelValues(name).Observe(time.Since(start).Seconds())
 	if err != nil {
-		log.Println("移除旧的端口分流失败：", err)
-	} else {
-		log.Println("移除旧的端口分流成功")
+		metrics.FetchSuccess.WithLabelValues(name).Set(0)
+		return nil, err
 	}
+	metrics.FetchSuccess.WithLabelValues(name).Set(1)
+	metrics.FetchBytes.WithLabelValues(name).Set(float64(len(body)))
+	return body, nil
+}
 
-	// 3. 应用新配置
-	applyCustomIsps(iKuai, newCustomIsps)
-	applyIpGroups(iKuai, newIpGroups)
-	applyStreamDomains(iKuai, newStreamDomains)
-	applyStreamIpPorts(iKuai, newStreamIpPorts)
+func contains(arr []string, s string) bool {
+	for _, v := range arr {
+		if v == s {
+			return true
+		}
+	}
+	return false
 }
 
 // 获取所有自定义运营商新配置
 func fetchAllCustomIsp() ([]customIspData, error) {
 	var result []customIspData
 	for _, cfg := range conf.CustomIsp {
-		resp, err := http.Get(cfg.URL)
-		if err != nil {
-			return nil, fmt.Errorf("获取%s配置失败: %v", cfg.Name, err)
-		}
-		defer resp.Body.Close()
+		var ips []string
 
-		if resp.StatusCode != 200 {
-			return nil, fmt.Errorf("%s返回状态码: %d", cfg.URL, resp.StatusCode)
-		}
+		if cfg.Type == "mmdb-isp" {
+			var err error
+			ips, err = mmdb.Scan(cfg.MmdbFile, cfg.Mmdb)
+			if err != nil {
+				return nil, fmt.Errorf("从mmdb文件生成%s配置失败: %v", cfg.Name, err)
+			}
+		} else {
+			src, err := source.New(cfg.Name, cfg.URL, cfg.sourceOpts.toOptions())
+			if err != nil {
+				return nil, fmt.Errorf("创建%s的源失败: %v", cfg.Name, err)
+			}
 
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return nil, fmt.Errorf("读取%s内容失败: %v", cfg.Name, err)
-		}
+			body, err := fetchSource(cfg.Name, src)
+			if err != nil {
+				return nil, fmt.Errorf("获取%s配置失败: %v", cfg.Name, err)
+			}
 
-		ips := strings.Split(string(body), "\n")
-		ips = removeIpv6(ips)
-		ipGroups := group(ips, 5000)
+			ips = strings.Split(string(body), "\n")
+		}
 
-		result = append(result, customIspData{
-			name:     cfg.Name,
-			ipGroups: ipGroups,
-		})
+		for _, entry := range splitByFamily(cfg.Name, ips, cfg.Family) {
+			result = append(result, customIspData{
+				name:     entry.name,
+				ipGroups: group(entry.cidrs, 5000),
+			})
+		}
 	}
 	return result, nil
 }
@@ -240,53 +588,97 @@ func fetchAllCustomIsp() ([]customIspData, error) {
 func fetchAllIpGroup() ([]ipGroupData, error) {
 	var result []ipGroupData
 	for _, cfg := range conf.IpGroup {
-		resp, err := http.Get(cfg.URL)
-		if err != nil {
-			return nil, fmt.Errorf("获取%s配置失败: %v", cfg.Name, err)
-		}
-		defer resp.Body.Close()
+		var ips []string
+
+		if cfg.Type == "mmdb-ip-group" {
+			var err error
+			ips, err = mmdb.Scan(cfg.MmdbFile, cfg.Mmdb)
+			if err != nil {
+				return nil, fmt.Errorf("从mmdb文件生成%s配置失败: %v", cfg.Name, err)
+			}
+		} else {
+			src, err := source.New(cfg.Name, cfg.URL, cfg.sourceOpts.toOptions())
+			if err != nil {
+				return nil, fmt.Errorf("创建%s的源失败: %v", cfg.Name, err)
+			}
 
-		if resp.StatusCode != 200 {
-			return nil, fmt.Errorf("%s返回状态码: %d", cfg.URL, resp.StatusCode)
+			body, err := fetchSource(cfg.Name, src)
+			if err != nil {
+				return nil, fmt.Errorf("获取%s配置失败: %v", cfg.Name, err)
+			}
+
+			ips = strings.Split(string(body), "\n")
 		}
 
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return nil, fmt.Errorf("读取%s内容失败: %v", cfg.Name, err)
+		for _, entry := range splitByFamily(cfg.Name, ips, cfg.Family) {
+			result = append(result, ipGroupData{
+				name:     entry.name,
+				ipGroups: group(entry.cidrs, 1000),
+			})
 		}
+	}
+	return result, nil
+}
+
+// familyGroup 是某个地址族筛选后、待分片的一份数据：name已经按family加好了后缀（双栈时v6那份加"_v6"）
+type familyGroup struct {
+	name  string
+	cidrs []string
+}
 
-		ips := strings.Split(string(body), "\n")
-		ips = removeIpv6(ips)
-		ipGroups := group(ips, 1000)
+// splitByFamily 按family配置把原始地址列表拆成一份或两份已去重规整的CIDR列表。
+// family为空或"v4"时只保留v4；"v6"只保留v6；"both"时v4和v6分别生成一份，v6那份name带"_v6"后缀。
+func splitByFamily(name string, entries []string, family string) []familyGroup {
+	v4, v6 := cidr.Split(entries)
 
-		result = append(result, ipGroupData{
-			name:     cfg.Name,
-			ipGroups: ipGroups,
-		})
+	var groups []familyGroup
+	if family == "" || family == "v4" || family == "both" {
+		if normalized := cidr.Normalize(v4); len(normalized) > 0 {
+			groups = append(groups, familyGroup{name: name, cidrs: normalized})
+		}
+	}
+	if family == "v6" || family == "both" {
+		if normalized := cidr.Normalize(v6); len(normalized) > 0 {
+			groups = append(groups, familyGroup{name: name + "_v6", cidrs: normalized})
+		}
+	}
+	return groups
+}
+
+// ipGroupNamesByFamily 把stream-ipport条目里引用的ip-group名字，按family配置展开成
+// splitByFamily实际生成出来的名字："v4"(默认)取原名，"v6"取"_v6"后缀版本，"both"两个都取。
+func ipGroupNamesByFamily(name string, family string) []string {
+	switch family {
+	case "v6":
+		return []string{name + "_v6"}
+	case "both":
+		return []string{name, name + "_v6"}
+	default:
+		return []string{name}
 	}
-	return result, nil
 }
 
 // 获取所有域名分流新配置
 func fetchAllStreamDomain() ([]streamDomainData, error) {
 	var result []streamDomainData
 	for _, cfg := range conf.StreamDomain {
-		resp, err := http.Get(cfg.URL)
+		src, err := source.New(cfg.Interface, cfg.URL, cfg.sourceOpts.toOptions())
 		if err != nil {
-			return nil, fmt.Errorf("获取%s配置失败: %v", cfg.URL, err)
+			return nil, fmt.Errorf("创建%s的源失败: %v", cfg.Interface, err)
 		}
-		defer resp.Body.Close()
 
-		if resp.StatusCode != 200 {
-			return nil, fmt.Errorf("%s返回状态码: %d", cfg.URL, resp.StatusCode)
+		body, err := fetchSource(cfg.Interface, src)
+		if err != nil {
+			return nil, fmt.Errorf("获取%s配置失败: %v", cfg.URL, err)
 		}
 
-		body, err := io.ReadAll(resp.Body)
+		domains, warnings, err := geosite.Parse(geosite.Format(cfg.Format), body, cfg.Tags)
 		if err != nil {
-			return nil, fmt.Errorf("读取%s内容失败: %v", cfg.URL, err)
+			return nil, fmt.Errorf("解析%s域名规则失败: %v", cfg.Interface, err)
+		}
+		for _, w := range warnings {
+			log.Printf("%s: %s", cfg.Interface, w)
 		}
-
-		domains := strings.Split(string(body), "\n")
 		domainGroups := group(domains, 1000)
 
 		result = append(result, streamDomainData{
@@ -304,11 +696,13 @@ func fetchAllStreamIpPort(iKuai *api.IKuai) ([]streamIpPortData, error) {
 	for _, cfg := range conf.StreamIpPort {
 		var ipGroupList []string
 		for _, ipGroupItem := range strings.Split(cfg.IpGroup, ",") {
-			data, err := iKuai.GetAllIKuaiBypassIpGroupNamesByName(ipGroupItem)
-			if err != nil {
-				return nil, fmt.Errorf("获取IP分组%s失败: %v", ipGroupItem, err)
+			for _, name := range ipGroupNamesByFamily(ipGroupItem, cfg.Family) {
+				data, err := iKuai.GetAllIKuaiBypassIpGroupNamesByName(name)
+				if err != nil {
+					return nil, fmt.Errorf("获取IP分组%s失败: %v", name, err)
+				}
+				ipGroupList = append(ipGroupList, data...)
 			}
-			ipGroupList = append(ipGroupList, data...)
 		}
 
 		result = append(result, streamIpPortData{
@@ -322,73 +716,6 @@ func fetchAllStreamIpPort(iKuai *api.IKuai) ([]streamIpPortData, error) {
 	return result, nil
 }
 
-// 应用自定义运营商配置
-func applyCustomIsps(iKuai *api.IKuai, dataList []customIspData) {
-	for _, data := range dataList {
-		for _, ig := range data.ipGroups {
-			ipGroup := strings.Join(ig, ",")
-			if err := iKuai.AddCustomIsp(data.name, ipGroup); err != nil {
-				log.Printf("添加自定义运营商'%s'失败: %v", data.name, err)
-			}
-		}
-		log.Printf("添加自定义运营商'%s'成功", data.name)
-	}
-}
-
-// 应用IP分组配置
-func applyIpGroups(iKuai *api.IKuai, dataList []ipGroupData) {
-	for _, data := range dataList {
-		for index, ig := range data.ipGroups {
-			ipGroup := strings.Join(ig, ",")
-			name := data.name + "_" + strconv.Itoa(index)
-			if err := iKuai.AddIpGroup(name, ipGroup); err != nil {
-				log.Printf("添加IP分组'%s'失败: %v", name, err)
-			}
-		}
-		log.Printf("添加IP分组'%s'成功", data.name)
-	}
-}
-
-// 应用域名分流配置
-func applyStreamDomains(iKuai *api.IKuai, dataList []streamDomainData) {
-	for _, data := range dataList {
-		for _, d := range data.domains {
-			domain := strings.Join(d, ",")
-			if err := iKuai.AddStreamDomain(data.iface, data.srcAddr, domain); err != nil {
-				log.Printf("添加域名分流'%s'失败: %v", data.iface, err)
-			}
-		}
-		log.Printf("添加域名分流'%s'成功", data.iface)
-	}
-}
-
-// 应用端口分流配置
-func applyStreamIpPorts(iKuai *api.IKuai, dataList []streamIpPortData) {
-	for _, data := range dataList {
-		if err := iKuai.AddStreamIpPort(
-			data.type_,
-			data.iface,
-			strings.Join(data.ipGroupList, ","),
-			data.srcAddr,
-			data.nexthop,
-		); err != nil {
-			log.Printf("添加端口分流'%s'失败: %v", data.iface, err)
-		}
-		log.Printf("添加端口分流'%s'成功", data.iface)
-	}
-}
-
-func removeIpv6(ips []string) []string {
-	i := 0
-	for _, ip := range ips {
-		if !strings.Contains(ip, ":") {
-			ips[i] = ip
-			i++
-		}
-	}
-	return ips[:i]
-}
-
 func group(arr []string, subGroupLength int64) [][]string {
 	max := int64(len(arr))
 	var segmens = make([][]string, 0)
@@ -0,0 +1,56 @@
+// Package metrics 定义本程序对外暴露的 Prometheus 指标，供 admin 包的 /metrics 端点使用，
+// 也供 main.go 在拉取/下发规则的各个阶段上报。
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+const namespace = "ikuai_bypass"
+
+var (
+	LastRunTimestamp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_run_timestamp_seconds",
+		Help:      "最近一次完整同步周期结束的unix时间戳",
+	})
+
+	FetchDurationSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "fetch_duration_seconds",
+		Help:      "单个规则源一次拉取耗时",
+	}, []string{"source"})
+
+	FetchBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "fetch_bytes",
+		Help:      "单个规则源最近一次拉取到的字节数",
+	}, []string{"source"})
+
+	FetchSuccess = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "fetch_success",
+		Help:      "单个规则源最近一次拉取是否拿到了内容，1是0否；拉取失败但回退到磁盘缓存时也记为1",
+	}, []string{"source"})
+
+	ApplyTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "apply_total",
+		Help:      "按规则类型统计的下发结果次数",
+	}, []string{"kind", "result"}) // result: success|failure
+
+	RulesInEffect = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "rules_in_effect",
+		Help:      "按规则类型统计的当前生效条目数",
+	}, []string{"kind"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		LastRunTimestamp,
+		FetchDurationSeconds,
+		FetchBytes,
+		FetchSuccess,
+		ApplyTotal,
+		RulesInEffect,
+	)
+}
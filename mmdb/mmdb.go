@@ -0,0 +1,80 @@
+// Package mmdb 从 MaxMind GeoIP2/GeoLite2 的 .mmdb 数据库中按国家/ASN/大洲筛选网段，
+// 生成可直接喂给 iKuai 自定义运营商/IP分组的 CIDR 列表。
+package mmdb
+
+import (
+	"fmt"
+
+	maxminddb "github.com/oschwald/maxminddb-golang"
+)
+
+// Selector 描述一次筛选所使用的条件，各字段之间是“或”的关系：
+// 网段只要命中其中任意一个条件就会被选中。全部为空时不做任何筛选，返回库内全部网段。
+type Selector struct {
+	Country   []string `yaml:"country"`
+	Asn       []int64  `yaml:"asn"`
+	Continent []string `yaml:"continent"`
+}
+
+// 数据库中用到的字段，GeoLite2-Country/GeoLite2-ASN 都能解到其中的子集
+type mmdbRecord struct {
+	Country struct {
+		IsoCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	Continent struct {
+		Code string `maxminddb:"code"`
+	} `maxminddb:"continent"`
+	AutonomousSystemNumber int64 `maxminddb:"autonomous_system_number"`
+}
+
+func (s Selector) empty() bool {
+	return len(s.Country) == 0 && len(s.Asn) == 0 && len(s.Continent) == 0
+}
+
+func (s Selector) match(r mmdbRecord) bool {
+	if s.empty() {
+		return true
+	}
+	for _, c := range s.Country {
+		if c == r.Country.IsoCode {
+			return true
+		}
+	}
+	for _, c := range s.Continent {
+		if c == r.Continent.Code {
+			return true
+		}
+	}
+	for _, asn := range s.Asn {
+		if asn == r.AutonomousSystemNumber {
+			return true
+		}
+	}
+	return false
+}
+
+// Scan 打开 path 指向的 mmdb 文件，按 sel 过滤后返回命中的 CIDR 列表。
+func Scan(path string, sel Selector) ([]string, error) {
+	db, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开mmdb文件%s失败: %v", path, err)
+	}
+	defer db.Close()
+
+	var result []string
+	networks := db.Networks(maxminddb.SkipAliasedNetworks)
+	for networks.Next() {
+		var record mmdbRecord
+		network, err := networks.Network(&record)
+		if err != nil {
+			return nil, fmt.Errorf("遍历mmdb文件%s失败: %v", path, err)
+		}
+		if sel.match(record) {
+			result = append(result, network.String())
+		}
+	}
+	if err := networks.Err(); err != nil {
+		return nil, fmt.Errorf("遍历mmdb文件%s失败: %v", path, err)
+	}
+	return result, nil
+}
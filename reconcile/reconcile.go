@@ -0,0 +1,63 @@
+// Package reconcile 计算"期望状态"和"当前状态"之间的最小变更集合，
+// 用于把 update() 里原来的"先整体删除、再整体重建"替换成按条目增删的增量同步。
+package reconcile
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+)
+
+// Entry 是当前已下发到 iKuai 的一条记录的摘要：内部ID，加上内容哈希
+type Entry struct {
+	ID   int
+	Hash string
+}
+
+// ContentHash 对构成一条规则的字段计算一个与字段顺序无关的稳定哈希，
+// 这样同一个分片内部的顺序变化不会被当成"内容变了"。
+func ContentHash(fields ...string) string {
+	sorted := append([]string(nil), fields...)
+	sort.Strings(sorted)
+
+	h := sha256.New()
+	for _, f := range sorted {
+		h.Write([]byte(f))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Plan 是一次 Diff 的结果：达到期望状态所需要的最小一组API调用
+type Plan struct {
+	ToAdd     []string // 期望存在、但iKuai上还没有的key
+	ToUpdate  []string // 期望存在、iKuai上也有，但内容哈希变了的key（需要先删旧记录再按新内容添加）
+	ToDelete  []int // 对应ToUpdate的旧记录ID，加上iKuai上多出来的、期望状态里已经没有的记录ID
+	Unchanged int   // 内容未变化、无需任何API调用的条目数，仅用于日志展示
+}
+
+// Diff 比较期望内容（key -> 内容哈希）和当前已下发的记录，计算出最小变更集合。
+func Diff(desired map[string]string, current map[string]Entry) Plan {
+	var plan Plan
+
+	for key, hash := range desired {
+		entry, ok := current[key]
+		switch {
+		case !ok:
+			plan.ToAdd = append(plan.ToAdd, key)
+		case entry.Hash != hash:
+			plan.ToUpdate = append(plan.ToUpdate, key)
+			plan.ToDelete = append(plan.ToDelete, entry.ID)
+		default:
+			plan.Unchanged++
+		}
+	}
+
+	for key, entry := range current {
+		if _, ok := desired[key]; !ok {
+			plan.ToDelete = append(plan.ToDelete, entry.ID)
+		}
+	}
+
+	return plan
+}
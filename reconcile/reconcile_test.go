@@ -0,0 +1,54 @@
+package reconcile
+
+import "testing"
+
+func TestContentHashOrderIndependent(t *testing.T) {
+	a := ContentHash("1.1.1.0/24", "2.2.2.0/24")
+	b := ContentHash("2.2.2.0/24", "1.1.1.0/24")
+	if a != b {
+		t.Fatalf("ContentHash应当与字段顺序无关: %q != %q", a, b)
+	}
+}
+
+func TestContentHashDiffersOnContent(t *testing.T) {
+	a := ContentHash("1.1.1.0/24")
+	b := ContentHash("1.1.1.0/25")
+	if a == b {
+		t.Fatal("不同内容不应该算出相同的哈希")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	desired := map[string]string{
+		"unchanged": "hash-a",
+		"changed":   "hash-b-new",
+		"added":     "hash-c",
+	}
+	current := map[string]Entry{
+		"unchanged": {ID: 1, Hash: "hash-a"},
+		"changed":   {ID: 2, Hash: "hash-b-old"},
+		"removed":   {ID: 3, Hash: "hash-d"},
+	}
+
+	plan := Diff(desired, current)
+
+	if plan.Unchanged != 1 {
+		t.Errorf("Unchanged = %d, want 1", plan.Unchanged)
+	}
+	if len(plan.ToAdd) != 1 || plan.ToAdd[0] != "added" {
+		t.Errorf("ToAdd = %v, want [added]", plan.ToAdd)
+	}
+	if len(plan.ToUpdate) != 1 || plan.ToUpdate[0] != "changed" {
+		t.Errorf("ToUpdate = %v, want [changed]", plan.ToUpdate)
+	}
+
+	wantDeleted := map[int]bool{2: true, 3: true}
+	if len(plan.ToDelete) != len(wantDeleted) {
+		t.Fatalf("ToDelete = %v, want ids for changed(2)+removed(3)", plan.ToDelete)
+	}
+	for _, id := range plan.ToDelete {
+		if !wantDeleted[id] {
+			t.Errorf("ToDelete包含意料之外的id %d", id)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package source
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// snapshot 是落盘缓存的内容，记录上一次成功拉取的结果以及用于条件请求的元数据
+type snapshot struct {
+	Body         []byte `json:"body"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// cachedSource 提供"拉取失败时回退到磁盘缓存"的公共逻辑，由各具体 Source 组合使用
+type cachedSource struct {
+	path string // 为空表示不启用磁盘缓存
+}
+
+func newCachedSource(name string, opts Options) *cachedSource {
+	if opts.CacheDir == "" {
+		return &cachedSource{}
+	}
+	sum := sha256.Sum256([]byte(name))
+	return &cachedSource{path: filepath.Join(opts.CacheDir, hex.EncodeToString(sum[:])+".json")}
+}
+
+func (c *cachedSource) enabled() bool {
+	return c.path != ""
+}
+
+func (c *cachedSource) load() (*snapshot, error) {
+	if !c.enabled() {
+		return nil, nil
+	}
+	buf, err := os.ReadFile(c.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var s snapshot
+	if err := json.Unmarshal(buf, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (c *cachedSource) save(s *snapshot) error {
+	if !c.enabled() {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+	buf, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, buf, 0o644)
+}
+
+// fetchOrFallback 执行 fn 拉取最新内容，失败时尝试回退到磁盘缓存的上一次快照；
+// 成功时把结果写回缓存，供下次拉取失败时使用。
+func (c *cachedSource) fetchOrFallback(fn func() (*snapshot, error)) ([]byte, error) {
+	fresh, err := fn()
+	if err == nil {
+		if saveErr := c.save(fresh); saveErr != nil {
+			log.Printf("写入缓存%s失败: %v", c.path, saveErr)
+		}
+		return fresh.Body, nil
+	}
+
+	cached, loadErr := c.load()
+	if loadErr != nil || cached == nil {
+		return nil, fmt.Errorf("拉取失败且无可用缓存: %v", err)
+	}
+	log.Printf("拉取失败，使用上一次成功的缓存内容: %v", err)
+	return cached.Body, nil
+}
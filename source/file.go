@@ -0,0 +1,19 @@
+package source
+
+import (
+	"fmt"
+	"os"
+)
+
+// fileSource 直接读取本地文件，不做缓存也不参与重试（本地文件拉取失败没有回退的意义）
+type fileSource struct {
+	path string
+}
+
+func (s *fileSource) Fetch() ([]byte, error) {
+	body, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("读取文件%s失败: %v", s.path, err)
+	}
+	return body, nil
+}
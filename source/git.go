@@ -0,0 +1,205 @@
+package source
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// gitSource 从形如 https://github.com/org/repo.git//path/to/file@ref 的地址中，
+// 对仓库做单路径的 sparse-checkout，读取其中一个文件的内容。
+// "//" 之后是仓库内的路径，"@" 之后是分支/tag/commit，两者均可省略（路径省略表示仓库根目录的唯一文件，ref 省略表示默认分支）。
+type gitSource struct {
+	repoURL string
+	path    string
+	ref     string
+	workdir string
+	opts    Options
+	cached  *cachedSource
+}
+
+func newGitSource(rawURL string, opts Options, cached *cachedSource) (*gitSource, error) {
+	repoURL := rawURL
+	ref := ""
+	if idx := strings.LastIndex(repoURL, "@"); idx >= 0 {
+		ref = repoURL[idx+1:]
+		repoURL = repoURL[:idx]
+	}
+
+	path := ""
+	if idx := strings.Index(repoURL, "//"); idx >= 0 {
+		// 跳过 "https://" 里的双斜杠，找仓库路径分隔符用的那一个
+		if schemeIdx := strings.Index(repoURL, "://"); schemeIdx >= 0 {
+			if sepIdx := strings.Index(repoURL[schemeIdx+3:], "//"); sepIdx >= 0 {
+				abs := schemeIdx + 3 + sepIdx
+				path = repoURL[abs+2:]
+				repoURL = repoURL[:abs]
+			}
+		}
+	}
+
+	if opts.CacheDir == "" {
+		return nil, fmt.Errorf("git源需要配置cache-dir作为工作目录: %s", rawURL)
+	}
+
+	return &gitSource{
+		repoURL: repoURL,
+		path:    path,
+		ref:     ref,
+		workdir: filepath.Join(opts.CacheDir, "git", sanitizeDirName(repoURL)),
+		opts:    opts,
+		cached:  cached,
+	}, nil
+}
+
+func sanitizeDirName(s string) string {
+	r := strings.NewReplacer("://", "_", "/", "_", ":", "_")
+	return r.Replace(s)
+}
+
+func (s *gitSource) Fetch() ([]byte, error) {
+	return s.cached.fetchOrFallback(func() (*snapshot, error) {
+		return retryFetch(s.opts.maxRetries(), s.opts.initialBackoff(), func() (*snapshot, error) {
+			return s.fetchOnce()
+		})
+	})
+}
+
+func (s *gitSource) fetchOnce() (*snapshot, error) {
+	if err := s.syncRepo(); err != nil {
+		return nil, err
+	}
+
+	full := filepath.Join(s.workdir, s.path)
+	if s.path == "" {
+		entries, err := os.ReadDir(s.workdir)
+		if err != nil {
+			return nil, fmt.Errorf("读取%s根目录失败: %v", s.repoURL, err)
+		}
+		var files []string
+		for _, e := range entries {
+			if !e.IsDir() {
+				files = append(files, e.Name())
+			}
+		}
+		if len(files) != 1 {
+			return nil, fmt.Errorf("%s根目录下有%d个文件，无法确定读取哪一个，需要在地址中用//指定路径", s.repoURL, len(files))
+		}
+		full = filepath.Join(s.workdir, files[0])
+	}
+
+	body, err := os.ReadFile(full)
+	if err != nil {
+		return nil, fmt.Errorf("读取%s#%s中的%s失败: %v", s.repoURL, s.ref, s.path, err)
+	}
+
+	if err := verifySha256(body, s.opts.Sha256); err != nil {
+		return nil, fmt.Errorf("%s#%s: %v", s.repoURL, s.path, err)
+	}
+
+	head, _ := os.ReadFile(filepath.Join(s.workdir, ".git", "HEAD"))
+	return &snapshot{Body: body, ETag: strings.TrimSpace(string(head))}, nil
+}
+
+// syncRepo 对 s.repoURL 做一次 sparse-checkout 式同步：首次克隆后工作区只检出 s.path 所在目录，之后复用同一目录做 pull。
+// ref 为 commit 时没有"拉取最新"这回事，每次都直接把工作区检出到这个commit。
+func (s *gitSource) syncRepo() error {
+	repo, err := git.PlainOpen(s.workdir)
+	if err == git.ErrRepositoryNotExists {
+		repo, err = s.clone()
+	}
+	if err != nil {
+		return fmt.Errorf("同步仓库%s失败: %v", s.repoURL, err)
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("打开%s工作区失败: %v", s.repoURL, err)
+	}
+
+	if isCommitHash(s.ref) {
+		if err := wt.Checkout(&git.CheckoutOptions{Hash: plumbing.NewHash(s.ref)}); err != nil {
+			return fmt.Errorf("检出%s的commit %s失败: %v", s.repoURL, s.ref, err)
+		}
+		return nil
+	}
+
+	if err := wt.Pull(&git.PullOptions{Depth: 1, Force: true}); err != nil && err != git.NoErrAlreadyUpToDate {
+		return fmt.Errorf("拉取%s最新内容失败: %v", s.repoURL, err)
+	}
+
+	return nil
+}
+
+// clone 按ref的形态选择克隆方式：ref是commit时仓库需要完整历史才能检出到任意提交，没法用Depth浅克隆；
+// ref是分支还是tag无法仅从字符串区分，按分支引用先试一次，失败再按tag引用重试。
+func (s *gitSource) clone() (*git.Repository, error) {
+	if isCommitHash(s.ref) {
+		return s.cloneSparse(&git.CloneOptions{URL: s.repoURL})
+	}
+
+	if s.ref == "" {
+		return s.cloneSparse(&git.CloneOptions{URL: s.repoURL, Depth: 1})
+	}
+
+	repo, err := s.cloneSparse(&git.CloneOptions{
+		URL:           s.repoURL,
+		Depth:         1,
+		ReferenceName: plumbing.NewBranchReferenceName(s.ref),
+	})
+	if err == nil {
+		return repo, nil
+	}
+
+	return s.cloneSparse(&git.CloneOptions{
+		URL:           s.repoURL,
+		Depth:         1,
+		ReferenceName: plumbing.NewTagReferenceName(s.ref),
+	})
+}
+
+// cloneSparse 先按opts完整克隆仓库，再用Worktree.Checkout把工作区收窄到s.path所在目录——
+// go-git的CloneOptions本身不支持按路径过滤，sparse-checkout只能在克隆完成后通过CheckoutOptions应用。
+func (s *gitSource) cloneSparse(opts *git.CloneOptions) (*git.Repository, error) {
+	repo, err := git.PlainClone(s.workdir, false, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("打开%s工作区失败: %v", s.repoURL, err)
+	}
+	if err := wt.Checkout(&git.CheckoutOptions{SparseCheckoutDirectories: sparseCheckoutDirs(s.path)}); err != nil {
+		return nil, fmt.Errorf("对%s应用sparse-checkout失败: %v", s.repoURL, err)
+	}
+
+	return repo, nil
+}
+
+// sparseCheckoutDirs 把目标文件所在目录作为sparse-checkout范围，仓库其余内容不检出到工作区（克隆时仍会下载）；
+// path为空（仓库根目录就是那一个文件）时退化成只取仓库根目录。
+func sparseCheckoutDirs(path string) []string {
+	dir := filepath.Dir(path)
+	if dir == "." || dir == "" {
+		return []string{"/"}
+	}
+	return []string{"/" + dir}
+}
+
+// isCommitHash 粗略判断ref是不是一段commit哈希（而不是分支/tag名）：7到40位十六进制字符。
+func isCommitHash(ref string) bool {
+	if len(ref) < 7 || len(ref) > 40 {
+		return false
+	}
+	for _, c := range ref {
+		if !((c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,100 @@
+package source
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+type httpSource struct {
+	url    string
+	opts   Options
+	cached *cachedSource
+}
+
+func (s *httpSource) Fetch() ([]byte, error) {
+	return s.cached.fetchOrFallback(func() (*snapshot, error) {
+		return retryFetch(s.opts.maxRetries(), s.opts.initialBackoff(), func() (*snapshot, error) {
+			return s.fetchOnce()
+		})
+	})
+}
+
+func (s *httpSource) fetchOnce() (*snapshot, error) {
+	req, err := http.NewRequest(http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("构造请求%s失败: %v", s.url, err)
+	}
+
+	var prev *snapshot
+	if s.opts.UseEtag {
+		prev, _ = s.cached.load()
+		if prev != nil {
+			if prev.ETag != "" {
+				req.Header.Set("If-None-Match", prev.ETag)
+			}
+			if prev.LastModified != "" {
+				req.Header.Set("If-Modified-Since", prev.LastModified)
+			}
+		}
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("获取%s失败: %v", s.url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && prev != nil {
+		return prev, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s返回状态码: %d", s.url, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取%s内容失败: %v", s.url, err)
+	}
+
+	if err := verifySha256(body, s.opts.Sha256); err != nil {
+		return nil, fmt.Errorf("%s: %v", s.url, err)
+	}
+
+	return &snapshot{
+		Body:         body,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}, nil
+}
+
+func retryFetch(maxRetries int, backoff time.Duration, fn func() (*snapshot, error)) (*snapshot, error) {
+	var lastErr error
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		s, err := fn()
+		if err == nil {
+			return s, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+func verifySha256(body []byte, want string) error {
+	if want == "" {
+		return nil
+	}
+	sum := sha256.Sum256(body)
+	got := hex.EncodeToString(sum[:])
+	if got != want {
+		return fmt.Errorf("sha256校验失败，期望%s实际%s", want, got)
+	}
+	return nil
+}
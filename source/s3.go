@@ -0,0 +1,62 @@
+package source
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// s3Source 从 s3://bucket/key 读取对象内容
+type s3Source struct {
+	bucket string
+	key    string
+	opts   Options
+	cached *cachedSource
+}
+
+func (s *s3Source) Fetch() ([]byte, error) {
+	return s.cached.fetchOrFallback(func() (*snapshot, error) {
+		return retryFetch(s.opts.maxRetries(), s.opts.initialBackoff(), func() (*snapshot, error) {
+			return s.fetchOnce()
+		})
+	})
+}
+
+func (s *s3Source) fetchOnce() (*snapshot, error) {
+	ctx := context.Background()
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("加载AWS配置失败: %v", err)
+	}
+
+	client := s3.NewFromConfig(cfg)
+	out, err := client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("获取s3://%s/%s失败: %v", s.bucket, s.key, err)
+	}
+	defer out.Body.Close()
+
+	buf := new(bytes.Buffer)
+	if _, err := io.Copy(buf, out.Body); err != nil {
+		return nil, fmt.Errorf("读取s3://%s/%s内容失败: %v", s.bucket, s.key, err)
+	}
+
+	body := buf.Bytes()
+	if err := verifySha256(body, s.opts.Sha256); err != nil {
+		return nil, fmt.Errorf("s3://%s/%s: %v", s.bucket, s.key, err)
+	}
+
+	etag := ""
+	if out.ETag != nil {
+		etag = *out.ETag
+	}
+	return &snapshot{Body: body, ETag: etag}, nil
+}
@@ -0,0 +1,76 @@
+// Package source 把"从哪里拿规则列表"这件事抽象成统一的 Source 接口，
+// 支持 http(s)://、file://、s3:// 和 git+https:// 四种来源，并在取失败时
+// 自动回退到磁盘缓存的上一次成功结果，避免单个源故障导致整个更新周期中止。
+package source
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Options 是构造 Source 时的公共配置，对应 YAML 里每个规则条目可选的缓存/校验/重试字段。
+type Options struct {
+	// CacheDir 为空时不做磁盘缓存
+	CacheDir string
+	// UseEtag 为 true 时对 http(s) 源附带 ETag/If-Modified-Since 做条件请求
+	UseEtag bool
+	// Sha256 非空时校验拉取到的内容摘要，不匹配视为本次拉取失败
+	Sha256 string
+	// MaxRetries 为 0 时表示使用默认值（3次）
+	MaxRetries int
+	// InitialBackoff 为 0 时表示使用默认值（1s），每次重试按 2 倍递增
+	InitialBackoff time.Duration
+}
+
+func (o Options) maxRetries() int {
+	if o.MaxRetries > 0 {
+		return o.MaxRetries
+	}
+	return 3
+}
+
+func (o Options) initialBackoff() time.Duration {
+	if o.InitialBackoff > 0 {
+		return o.InitialBackoff
+	}
+	return time.Second
+}
+
+// Source 从某处拉取一份规则列表的原始内容
+type Source interface {
+	// Fetch 返回规则列表的原始字节内容。拉取失败且存在可用缓存时，返回缓存内容而不是错误，
+	// 仅当既拉取失败又没有缓存可用时才返回 error。
+	Fetch() ([]byte, error)
+}
+
+// New 根据 rawURL 的 scheme 构造对应的 Source 实现。
+func New(name, rawURL string, opts Options) (Source, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("解析源地址%s失败: %v", rawURL, err)
+	}
+
+	base := newCachedSource(name, opts)
+
+	switch {
+	case u.Scheme == "http" || u.Scheme == "https":
+		return &httpSource{url: rawURL, opts: opts, cached: base}, nil
+	case u.Scheme == "file":
+		return &fileSource{path: filePathFromURL(u)}, nil
+	case u.Scheme == "s3":
+		return &s3Source{bucket: u.Host, key: strings.TrimPrefix(u.Path, "/"), opts: opts, cached: base}, nil
+	case strings.HasPrefix(rawURL, "git+https://"):
+		return newGitSource(strings.TrimPrefix(rawURL, "git+"), opts, base)
+	default:
+		return nil, fmt.Errorf("不支持的源类型: %s", rawURL)
+	}
+}
+
+func filePathFromURL(u *url.URL) string {
+	if u.Host != "" {
+		return u.Host + u.Path
+	}
+	return u.Path
+}